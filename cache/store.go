@@ -0,0 +1,213 @@
+// Package cache provides a local SQLite-backed store for dataset
+// records, so repeated searches don't have to re-hit the Socrata API
+// and burn the app token's hourly quota.
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store is a local cache of dataset records, keyed by dataset ID plus
+// each record's license_number and license_type.
+type Store struct {
+	db *sql.DB
+}
+
+// Open creates (if necessary) and opens the SQLite database at path.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening cache %q: %w", path, err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS records (
+			dataset TEXT NOT NULL,
+			license_number TEXT NOT NULL,
+			license_type TEXT NOT NULL,
+			updated_at TEXT,
+			data TEXT NOT NULL,
+			PRIMARY KEY (dataset, license_number, license_type)
+		);
+		CREATE TABLE IF NOT EXISTS sync_state (
+			dataset TEXT PRIMARY KEY,
+			last_synced_at TEXT,
+			max_updated_at TEXT
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("error migrating cache schema: %w", err)
+	}
+	return nil
+}
+
+// Close releases the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Upsert inserts or replaces records for dataset, keyed by each
+// record's license_number and license_type, and advances the
+// dataset's max :updated_at so the next sync can ask for only what
+// changed since. It returns how many rows were written.
+func (s *Store) Upsert(dataset string, records []json.RawMessage) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting cache transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO records (dataset, license_number, license_type, updated_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (dataset, license_number, license_type)
+		DO UPDATE SET updated_at = excluded.updated_at, data = excluded.data
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("error preparing cache upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	var maxUpdatedAt string
+	for _, raw := range records {
+		licenseNumber, licenseType, updatedAt, err := recordKey(raw)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := stmt.Exec(dataset, licenseNumber, licenseType, updatedAt, string(raw)); err != nil {
+			return 0, fmt.Errorf("error upserting record: %w", err)
+		}
+		if updatedAt > maxUpdatedAt {
+			maxUpdatedAt = updatedAt
+		}
+	}
+
+	if maxUpdatedAt != "" {
+		if _, err := tx.Exec(`
+			INSERT INTO sync_state (dataset, last_synced_at, max_updated_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (dataset) DO UPDATE SET
+				last_synced_at = excluded.last_synced_at,
+				max_updated_at = MAX(sync_state.max_updated_at, excluded.max_updated_at)
+		`, dataset, time.Now().UTC().Format(time.RFC3339), maxUpdatedAt); err != nil {
+			return 0, fmt.Errorf("error updating sync state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing cache transaction: %w", err)
+	}
+
+	return len(records), nil
+}
+
+// recordKey pulls the fields a cached record is keyed and ordered by
+// out of its raw JSON.
+func recordKey(raw json.RawMessage) (licenseNumber, licenseType, updatedAt string, err error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "", "", "", fmt.Errorf("error decoding record for cache: %w", err)
+	}
+	return StringField(fields["license_number"]), StringField(fields["license_type"]), StringField(fields[":updated_at"]), nil
+}
+
+// StringField renders a raw JSON field as a string for filtering or
+// display: a JSON string decodes to its value, and anything else
+// (numbers, bools, null, nested objects - Socrata serializes plenty of
+// columns unquoted) falls back to its raw JSON text rather than erroring.
+func StringField(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return string(raw)
+	}
+	return s
+}
+
+// MaxUpdatedAt returns the newest Socrata :updated_at value cached for
+// dataset, or "" if nothing has been synced yet.
+func (s *Store) MaxUpdatedAt(dataset string) (string, error) {
+	var maxUpdatedAt sql.NullString
+	err := s.db.QueryRow(`SELECT max_updated_at FROM sync_state WHERE dataset = ?`, dataset).Scan(&maxUpdatedAt)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading sync state: %w", err)
+	}
+	return maxUpdatedAt.String, nil
+}
+
+// Records returns every cached record for dataset.
+func (s *Store) Records(dataset string) ([]json.RawMessage, error) {
+	rows, err := s.db.Query(`SELECT data FROM records WHERE dataset = ?`, dataset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying cache: %w", err)
+	}
+	defer rows.Close()
+
+	var records []json.RawMessage
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("error scanning cached record: %w", err)
+		}
+		records = append(records, json.RawMessage(data))
+	}
+	return records, rows.Err()
+}
+
+// Stats summarizes a dataset's cached rows.
+type Stats struct {
+	Dataset      string
+	RecordCount  int
+	LastSyncedAt string
+	MaxUpdatedAt string
+}
+
+// Stats reports how many records are cached for dataset and when it
+// was last synced. LastSyncedAt only moves when a sync writes records
+// carrying an :updated_at; a sync that fetches nothing leaves it as is.
+func (s *Store) Stats(dataset string) (Stats, error) {
+	stats := Stats{Dataset: dataset}
+
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM records WHERE dataset = ?`, dataset).Scan(&stats.RecordCount); err != nil {
+		return Stats{}, fmt.Errorf("error counting cached records: %w", err)
+	}
+
+	var lastSyncedAt, maxUpdatedAt sql.NullString
+	err := s.db.QueryRow(`SELECT last_synced_at, max_updated_at FROM sync_state WHERE dataset = ?`, dataset).
+		Scan(&lastSyncedAt, &maxUpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return Stats{}, fmt.Errorf("error reading sync state: %w", err)
+	}
+	stats.LastSyncedAt = lastSyncedAt.String
+	stats.MaxUpdatedAt = maxUpdatedAt.String
+
+	return stats, nil
+}
+
+// Vacuum reclaims space left behind by updated or deleted rows.
+func (s *Store) Vacuum() error {
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("error vacuuming cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestUpsertThenRecordsRoundTrips(t *testing.T) {
+	s := openTestStore(t)
+
+	records := []json.RawMessage{
+		json.RawMessage(`{"license_number":"1","license_type":"A","business_name":"Alpha"}`),
+		json.RawMessage(`{"license_number":"2","license_type":"A","business_name":"Beta"}`),
+	}
+	n, err := s.Upsert("tdlr-licenses", records)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Upsert() = %d, want 2", n)
+	}
+
+	got, err := s.Records("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Records() returned %d rows, want 2", len(got))
+	}
+}
+
+func TestUpsertReplacesByPrimaryKey(t *testing.T) {
+	s := openTestStore(t)
+
+	if _, err := s.Upsert("tdlr-licenses", []json.RawMessage{
+		json.RawMessage(`{"license_number":"1","license_type":"A","business_name":"Old"}`),
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if _, err := s.Upsert("tdlr-licenses", []json.RawMessage{
+		json.RawMessage(`{"license_number":"1","license_type":"A","business_name":"New"}`),
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	got, err := s.Records("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Records() returned %d rows, want 1 (upsert should replace, not duplicate)", len(got))
+	}
+	if string(got[0]) != `{"license_number":"1","license_type":"A","business_name":"New"}` {
+		t.Errorf("Records()[0] = %s, want the updated row", got[0])
+	}
+}
+
+func TestUpsertTracksMaxUpdatedAt(t *testing.T) {
+	s := openTestStore(t)
+
+	records := []json.RawMessage{
+		json.RawMessage(`{"license_number":"1","license_type":"A",":updated_at":"2024-01-01T00:00:00.000Z"}`),
+		json.RawMessage(`{"license_number":"2","license_type":"A",":updated_at":"2024-06-01T00:00:00.000Z"}`),
+	}
+	if _, err := s.Upsert("tdlr-licenses", records); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	max, err := s.MaxUpdatedAt("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("MaxUpdatedAt() error = %v", err)
+	}
+	if max != "2024-06-01T00:00:00.000Z" {
+		t.Errorf("MaxUpdatedAt() = %q, want the newest :updated_at seen", max)
+	}
+
+	stats, err := s.Stats("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.RecordCount != 2 {
+		t.Errorf("Stats().RecordCount = %d, want 2", stats.RecordCount)
+	}
+	if stats.MaxUpdatedAt != max {
+		t.Errorf("Stats().MaxUpdatedAt = %q, want %q", stats.MaxUpdatedAt, max)
+	}
+	if stats.LastSyncedAt == "" {
+		t.Error("Stats().LastSyncedAt is empty after a sync that advanced :updated_at")
+	}
+}
+
+func TestMaxUpdatedAtEmptyBeforeFirstSync(t *testing.T) {
+	s := openTestStore(t)
+
+	max, err := s.MaxUpdatedAt("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("MaxUpdatedAt() error = %v", err)
+	}
+	if max != "" {
+		t.Errorf("MaxUpdatedAt() = %q, want empty for a never-synced dataset", max)
+	}
+}
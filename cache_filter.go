@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Sc00ba/texas_license_search/cache"
+)
+
+// matchesFilters reports whether a cached record satisfies every filter
+// configured on sReq. It mirrors buildPredicate's semantics, but
+// evaluates them in Go against an already-decoded record instead of
+// compiling them to SoQL, since cached records are matched locally
+// rather than by the Socrata API.
+func matchesFilters(raw json.RawMessage, sReq searchRequest) (bool, error) {
+	var rawFields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rawFields); err != nil {
+		return false, fmt.Errorf("error decoding cached record: %w", err)
+	}
+	fields := make(map[string]string, len(rawFields))
+	for name, raw := range rawFields {
+		fields[name] = cache.StringField(raw)
+	}
+
+	for _, f := range sReq.dataset.Fields() {
+		value := sReq.fieldFilters[f.Name]
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(strings.ToUpper(fields[f.Name]), strings.ToUpper(value)) {
+			return false, nil
+		}
+	}
+
+	for _, arg := range sReq.eqFilters {
+		field, value, err := splitFieldValue("eq", arg)
+		if err != nil {
+			return false, err
+		}
+		if fields[field] != value {
+			return false, nil
+		}
+	}
+
+	for _, arg := range sReq.prefixFilters {
+		field, value, err := splitFieldValue("prefix", arg)
+		if err != nil {
+			return false, err
+		}
+		if !strings.HasPrefix(strings.ToUpper(fields[field]), strings.ToUpper(value)) {
+			return false, nil
+		}
+	}
+
+	for _, arg := range sReq.inFilters {
+		field, value, err := splitFieldValue("in", arg)
+		if err != nil {
+			return false, err
+		}
+		matched := false
+		for _, option := range strings.Split(value, ",") {
+			if fields[field] == option {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	for _, arg := range sReq.betweenFilters {
+		field, value, err := splitFieldValue("between", arg)
+		if err != nil {
+			return false, err
+		}
+		bounds := strings.SplitN(value, "..", 2)
+		if len(bounds) != 2 {
+			return false, fmt.Errorf("--between expects field=low..high, got %q", arg)
+		}
+		if v := fields[field]; v < bounds[0] || v > bounds[1] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Sc00ba/texas_license_search/datasource"
+)
+
+func testDataset(t *testing.T) datasource.Dataset {
+	t.Helper()
+	registry, err := datasource.NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	ds, err := registry.Get("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	return ds
+}
+
+// A real synced row can carry numbers, nulls, and nested objects
+// alongside the declared filter fields (Socrata serializes plenty of
+// columns unquoted, and syncDataset selects "*"). matchesFilters must
+// not choke decoding those, even when no filter targets them.
+func TestMatchesFiltersToleratesNonStringFields(t *testing.T) {
+	raw := json.RawMessage(`{
+		"license_number": 90210,
+		"license_type": "A/C Technician",
+		"business_name": "Bob's Plumbing",
+		"location": {"latitude": "30.1", "longitude": "-97.7"},
+		"closed_date": null
+	}`)
+
+	sReq := searchRequest{
+		dataset:      testDataset(t),
+		fieldFilters: map[string]string{"business_name": "plumbing"},
+	}
+
+	ok, err := matchesFilters(raw, sReq)
+	if err != nil {
+		t.Fatalf("matchesFilters() error = %v, want no error for a record with non-string fields", err)
+	}
+	if !ok {
+		t.Error("matchesFilters() = false, want true (business_name matches)")
+	}
+}
+
+func TestMatchesFiltersMatchesNumericFieldAsString(t *testing.T) {
+	raw := json.RawMessage(`{"license_number": 90210, "license_type": "A/C Technician"}`)
+
+	sReq := searchRequest{
+		dataset:      testDataset(t),
+		fieldFilters: map[string]string{"license_number": "902"},
+	}
+
+	ok, err := matchesFilters(raw, sReq)
+	if err != nil {
+		t.Fatalf("matchesFilters() error = %v", err)
+	}
+	if !ok {
+		t.Error("matchesFilters() = false, want true (license_number 90210 contains 902)")
+	}
+}
+
+func TestMatchesFiltersRejectsNonMatchingFilter(t *testing.T) {
+	raw := json.RawMessage(`{"license_number": 90210, "license_type": "A/C Technician"}`)
+
+	sReq := searchRequest{
+		dataset:      testDataset(t),
+		fieldFilters: map[string]string{"license_type": "Plumber"},
+	}
+
+	ok, err := matchesFilters(raw, sReq)
+	if err != nil {
+		t.Fatalf("matchesFilters() error = %v", err)
+	}
+	if ok {
+		t.Error("matchesFilters() = true, want false (license_type doesn't match)")
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Sc00ba/texas_license_search/cache"
+	"github.com/Sc00ba/texas_license_search/datasource"
+	"github.com/Sc00ba/texas_license_search/soql"
+)
+
+// runCacheCommand handles `texas_license_search cache <subcommand>`.
+func runCacheCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: texas_license_search cache <sync|stats|vacuum> [flags]")
+	}
+
+	switch args[0] {
+	case "sync":
+		runCacheSync(args[1:])
+	case "stats":
+		runCacheStats(args[1:])
+	case "vacuum":
+		runCacheVacuum(args[1:])
+	default:
+		log.Fatalf("unknown cache subcommand %q", args[0])
+	}
+}
+
+func openDataset(datasetName, datasetFile string) datasource.Dataset {
+	registry, err := datasource.NewRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if datasetFile != "" {
+		if err := registry.LoadFile(datasetFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	ds, err := registry.Get(datasetName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return ds
+}
+
+func runCacheSync(args []string) {
+	fs := flag.NewFlagSet("cache sync", flag.ExitOnError)
+	cachePath := fs.String("cache", "texas_license_search.db", "Path to the local cache database")
+	datasetName := fs.String("dataset", "tdlr-licenses", "Dataset to sync")
+	datasetFile := fs.String("dataset-file", "", "Path to a YAML or JSON dataset definition to register under --dataset")
+	full := fs.Bool("full", false, "Ignore the cached max :updated_at and re-pull everything")
+	fs.Parse(args)
+
+	appToken := os.Getenv("APP_TOKEN")
+	if appToken == "" {
+		log.Fatal("Didn't find required APP_TOKEN in env")
+	}
+
+	ds := openDataset(*datasetName, *datasetFile)
+
+	store, err := cache.Open(*cachePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	total, err := syncDataset(ctx, store, ds, appToken, *full)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Synced %d records into %s\n", total, *cachePath)
+}
+
+// syncDataset pulls every record (or, unless full is set, only those
+// changed since the cache's last sync) for ds into store.
+func syncDataset(ctx context.Context, store *cache.Store, ds datasource.Dataset, appToken string, full bool) (int, error) {
+	// Socrata omits colon-prefixed system fields like :updated_at from
+	// the response unless explicitly selected, so the incremental sync
+	// needs `*, :updated_at` rather than the default unrestricted select.
+	query := soql.NewQuery().Select("*", ":updated_at").OrderBy(":updated_at", false)
+	if !full {
+		since, err := store.MaxUpdatedAt(ds.ID())
+		if err != nil {
+			return 0, err
+		}
+		if since != "" {
+			query = query.Where(soql.GreaterThan{Column: ":updated_at", Value: since})
+		}
+	}
+
+	records := make(chan json.RawMessage, 5000)
+	errs := make(chan error, 1)
+
+	sReq := searchRequest{
+		appToken:          appToken,
+		records:           records,
+		errs:              errs,
+		timeOutSecs:       30,
+		recordsPerRequest: 5000,
+		concurrency:       4,
+		qps:               2,
+		burst:             4,
+		retry:             defaultRetryConfig,
+		metrics:           &fetchMetrics{},
+		dataset:           ds,
+		query:             query,
+	}
+
+	go searchRecords(ctx, sReq)
+
+	const batchSize = 500
+	batch := make([]json.RawMessage, 0, batchSize)
+	total := 0
+
+	for records != nil || errs != nil {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				records = nil
+				continue
+			}
+			batch = append(batch, record)
+			if len(batch) >= batchSize {
+				if _, err := store.Upsert(ds.ID(), batch); err != nil {
+					return total, err
+				}
+				total += len(batch)
+				batch = batch[:0]
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			return total, err
+		}
+	}
+
+	if len(batch) > 0 {
+		if _, err := store.Upsert(ds.ID(), batch); err != nil {
+			return total, err
+		}
+		total += len(batch)
+	}
+
+	return total, nil
+}
+
+func runCacheStats(args []string) {
+	fs := flag.NewFlagSet("cache stats", flag.ExitOnError)
+	cachePath := fs.String("cache", "texas_license_search.db", "Path to the local cache database")
+	datasetName := fs.String("dataset", "tdlr-licenses", "Dataset to report on")
+	datasetFile := fs.String("dataset-file", "", "Path to a YAML or JSON dataset definition to register under --dataset")
+	fs.Parse(args)
+
+	ds := openDataset(*datasetName, *datasetFile)
+
+	store, err := cache.Open(*cachePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	stats, err := store.Stats(ds.ID())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Dataset:        %s\n", *datasetName)
+	fmt.Printf("Records cached: %d\n", stats.RecordCount)
+	fmt.Printf("Last synced at: %s\n", valueOrNone(stats.LastSyncedAt))
+	fmt.Printf("Max updated at: %s\n", valueOrNone(stats.MaxUpdatedAt))
+}
+
+func runCacheVacuum(args []string) {
+	fs := flag.NewFlagSet("cache vacuum", flag.ExitOnError)
+	cachePath := fs.String("cache", "texas_license_search.db", "Path to the local cache database")
+	fs.Parse(args)
+
+	store, err := cache.Open(*cachePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := store.Vacuum(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Cache vacuumed")
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// needsRefresh reports whether lastSyncedAt (an RFC3339 timestamp, or
+// "" if never synced) is older than maxAge.
+func needsRefresh(lastSyncedAt string, maxAge time.Duration) bool {
+	if lastSyncedAt == "" {
+		return true
+	}
+	syncedAt, err := time.Parse(time.RFC3339, lastSyncedAt)
+	if err != nil {
+		return true
+	}
+	return time.Since(syncedAt) > maxAge
+}
+
+// searchCache serves sReq from the local cache at cachePath instead of
+// the network, refreshing it first if it's older than
+// sReq.refreshIfOlderThan.
+func searchCache(ctx context.Context, sReq searchRequest, cachePath string) {
+	defer close(sReq.records)
+	defer close(sReq.errs)
+
+	store, err := cache.Open(cachePath)
+	if err != nil {
+		sReq.errs <- err
+		return
+	}
+	defer store.Close()
+
+	if sReq.refreshIfOlderThan > 0 {
+		stats, err := store.Stats(sReq.dataset.ID())
+		if err != nil {
+			sReq.errs <- err
+			return
+		}
+		if needsRefresh(stats.LastSyncedAt, sReq.refreshIfOlderThan) {
+			if _, err := syncDataset(ctx, store, sReq.dataset, sReq.appToken, false); err != nil {
+				sReq.errs <- fmt.Errorf("error refreshing cache: %w", err)
+				return
+			}
+		}
+	}
+
+	records, err := store.Records(sReq.dataset.ID())
+	if err != nil {
+		sReq.errs <- err
+		return
+	}
+
+	emitted := 0
+	for _, record := range records {
+		if sReq.limit > 0 && emitted >= sReq.limit {
+			return
+		}
+
+		ok, err := matchesFilters(record, sReq)
+		if err != nil {
+			sReq.errs <- err
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		select {
+		case sReq.records <- record:
+			emitted++
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package datasource describes the Socrata (or Socrata-compatible)
+// datasets the tool knows how to search, so the dataset ID, endpoint,
+// and filterable columns aren't hard-coded into the search path.
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FieldType names the scalar type of a dataset's filterable column.
+type FieldType string
+
+const (
+	FieldString FieldType = "string"
+	FieldDate   FieldType = "date"
+	FieldNumber FieldType = "number"
+	FieldEnum   FieldType = "enum"
+)
+
+// FieldSpec describes one filterable column a Dataset exposes.
+type FieldSpec struct {
+	Name string    `yaml:"name" json:"name"`
+	Flag string    `yaml:"flag" json:"flag"`
+	Type FieldType `yaml:"type" json:"type"`
+	Help string    `yaml:"help" json:"help"`
+}
+
+// Record is a single decoded row from a Dataset.
+type Record = json.RawMessage
+
+// Dataset describes a searchable Socrata dataset: where to find it,
+// which columns can be filtered on, and how to decode a row.
+type Dataset interface {
+	ID() string
+	BaseURL() string
+	Fields() []FieldSpec
+	DefaultSort() string
+	Decode(raw json.RawMessage) (Record, error)
+}
+
+// Spec is the on-disk (YAML or JSON) description of a Dataset, as
+// loaded by a Registry.
+type Spec struct {
+	Name        string      `yaml:"name" json:"name"`
+	ID          string      `yaml:"id" json:"id"`
+	BaseURL     string      `yaml:"base_url" json:"base_url"`
+	Fields      []FieldSpec `yaml:"fields" json:"fields"`
+	DefaultSort string      `yaml:"default_sort" json:"default_sort"`
+}
+
+// specDataset adapts a Spec to the Dataset interface.
+type specDataset struct {
+	spec Spec
+}
+
+func (d specDataset) ID() string          { return d.spec.ID }
+func (d specDataset) BaseURL() string     { return d.spec.BaseURL }
+func (d specDataset) Fields() []FieldSpec { return d.spec.Fields }
+func (d specDataset) DefaultSort() string { return d.spec.DefaultSort }
+
+// Decode validates raw as a JSON record. Datasets loaded from a Spec
+// have no further structure to enforce, so this is just a sanity check;
+// a hand-written Dataset could decode into a richer type instead.
+func (d specDataset) Decode(raw json.RawMessage) (Record, error) {
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid JSON record for dataset %q", d.spec.Name)
+	}
+	return raw, nil
+}
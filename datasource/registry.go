@@ -0,0 +1,95 @@
+package datasource
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed datasets/*.yaml
+var builtinDatasets embed.FS
+
+// Registry holds the datasets available to --dataset by name.
+type Registry struct {
+	datasets map[string]Dataset
+}
+
+// NewRegistry loads every dataset shipped with the binary under
+// datasource/datasets/*.yaml.
+func NewRegistry() (*Registry, error) {
+	r := &Registry{datasets: make(map[string]Dataset)}
+
+	entries, err := builtinDatasets.ReadDir("datasets")
+	if err != nil {
+		return nil, fmt.Errorf("error reading built-in datasets: %w", err)
+	}
+
+	for _, entry := range entries {
+		raw, err := builtinDatasets.ReadFile(path.Join("datasets", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading dataset %q: %w", entry.Name(), err)
+		}
+		if err := r.loadYAML(raw); err != nil {
+			return nil, fmt.Errorf("error loading dataset %q: %w", entry.Name(), err)
+		}
+	}
+
+	return r, nil
+}
+
+// Get returns a registered dataset by name.
+func (r *Registry) Get(name string) (Dataset, error) {
+	d, ok := r.datasets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dataset %q (known: %s)", name, strings.Join(r.Names(), ", "))
+	}
+	return d, nil
+}
+
+// Names lists every registered dataset name, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.datasets))
+	for name := range r.datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFile registers a user-supplied dataset definition from filePath,
+// detecting YAML vs JSON by file extension.
+func (r *Registry) LoadFile(filePath string) error {
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading dataset file %q: %w", filePath, err)
+	}
+
+	if strings.HasSuffix(filePath, ".json") {
+		return r.loadJSON(raw)
+	}
+	return r.loadYAML(raw)
+}
+
+func (r *Registry) loadYAML(raw []byte) error {
+	var spec Spec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return err
+	}
+	r.datasets[spec.Name] = specDataset{spec: spec}
+	return nil
+}
+
+func (r *Registry) loadJSON(raw []byte) error {
+	var spec Spec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return err
+	}
+	r.datasets[spec.Name] = specDataset{spec: spec}
+	return nil
+}
@@ -0,0 +1,120 @@
+package datasource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRegistryLoadsBuiltinDatasets(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	ds, err := registry.Get("tdlr-licenses")
+	if err != nil {
+		t.Fatalf("Get(\"tdlr-licenses\") error = %v", err)
+	}
+	if ds.ID() != "7358-krk7" {
+		t.Errorf("ID() = %q, want 7358-krk7", ds.ID())
+	}
+	if ds.DefaultSort() != "license_number" {
+		t.Errorf("DefaultSort() = %q, want license_number", ds.DefaultSort())
+	}
+	if len(ds.Fields()) == 0 {
+		t.Error("Fields() is empty, want the TDLR field spec")
+	}
+}
+
+func TestRegistryGetUnknownDatasetListsKnownNames(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	_, err = registry.Get("no-such-dataset")
+	if err == nil {
+		t.Fatal("Get() error = nil, want an error for an unknown dataset")
+	}
+	if got := err.Error(); !strings.Contains(got, "tdlr-licenses") {
+		t.Errorf("Get() error = %q, want it to list known dataset names", got)
+	}
+}
+
+func TestLoadFileYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	if err := os.WriteFile(path, []byte(`
+name: custom-dataset
+id: abcd-1234
+base_url: https://example.com/resource/abcd-1234.json
+default_sort: id
+fields:
+  - name: id
+    flag: id
+    type: string
+    help: The record id
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	ds, err := registry.Get("custom-dataset")
+	if err != nil {
+		t.Fatalf("Get(\"custom-dataset\") error = %v", err)
+	}
+	if ds.ID() != "abcd-1234" {
+		t.Errorf("ID() = %q, want abcd-1234", ds.ID())
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	if err := os.WriteFile(path, []byte(`{
+		"name": "custom-json-dataset",
+		"id": "wxyz-5678",
+		"base_url": "https://example.com/resource/wxyz-5678.json",
+		"default_sort": "id",
+		"fields": [{"name": "id", "flag": "id", "type": "string", "help": "The record id"}]
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	ds, err := registry.Get("custom-json-dataset")
+	if err != nil {
+		t.Fatalf("Get(\"custom-json-dataset\") error = %v", err)
+	}
+	if ds.ID() != "wxyz-5678" {
+		t.Errorf("ID() = %q, want wxyz-5678", ds.ID())
+	}
+}
+
+func TestNamesIsSorted(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	names := registry.Names()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			t.Errorf("Names() = %v, not sorted", names)
+			break
+		}
+	}
+}
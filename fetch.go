@@ -0,0 +1,282 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// page identifies one $limit/$offset slice of a query.
+type page struct {
+	offset int
+	limit  int
+}
+
+// pageResult is the outcome of fetching a single page.
+type pageResult struct {
+	offset  int
+	records []json.RawMessage
+	err     error
+}
+
+// searchRecords fetches sReq.query concurrently across sReq.concurrency
+// workers and publishes results on sReq.records. It first probes the
+// total record count so pages can be dispatched up front, then streams
+// pages as they complete unless sReq.ordered requests offset order.
+func searchRecords(ctx context.Context, sReq searchRequest) {
+	defer close(sReq.records)
+	defer close(sReq.errs)
+
+	client := newRetryingClient(time.Duration(sReq.timeOutSecs)*time.Second, sReq.qps, sReq.burst, sReq.retry, sReq.metrics)
+
+	total, err := probeCount(ctx, client, sReq)
+	if err != nil {
+		sReq.errs <- fmt.Errorf("error probing record count: %w", err)
+		return
+	}
+
+	if sReq.limit > 0 && sReq.limit < total {
+		total = sReq.limit
+	}
+	if total == 0 {
+		return
+	}
+
+	pages := buildPages(total, sReq.recordsPerRequest)
+
+	concurrency := sReq.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(pages) {
+		concurrency = len(pages)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan page)
+	results := make(chan pageResult)
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fetchWorker(ctx, client, sReq, jobs, results)
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, p := range pages {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	if sReq.ordered {
+		emitOrdered(ctx, sReq, results, cancel)
+	} else {
+		emitAsCompleted(ctx, sReq, results, cancel)
+	}
+}
+
+// buildPages splits total records into sequential offset/limit pages of
+// at most pageSize records each.
+func buildPages(total, pageSize int) []page {
+	if pageSize <= 0 {
+		pageSize = total
+	}
+	var pages []page
+	for offset := 0; offset < total; offset += pageSize {
+		limit := pageSize
+		if remaining := total - offset; remaining < limit {
+			limit = remaining
+		}
+		pages = append(pages, page{offset: offset, limit: limit})
+	}
+	return pages
+}
+
+// fetchWorker pulls pages off jobs until it's empty or ctx is canceled,
+// publishing one pageResult per page to results.
+func fetchWorker(ctx context.Context, client *retryingClient, sReq searchRequest, jobs <-chan page, results chan<- pageResult) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p, ok := <-jobs:
+			if !ok {
+				return
+			}
+			records, err := fetchPage(ctx, client, sReq, p)
+			select {
+			case results <- pageResult{offset: p.offset, records: records, err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// fetchPage retrieves a single page of sReq.query.
+func fetchPage(ctx context.Context, client *retryingClient, sReq searchRequest, p page) ([]json.RawMessage, error) {
+	pageQuery := *sReq.query
+	params := pageQuery.Limit(p.limit).Offset(p.offset).Values()
+
+	body, err := doRequest(ctx, client, sReq.dataset.BaseURL(), sReq.appToken, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawRecords []json.RawMessage
+	if err := json.Unmarshal(body, &rawRecords); err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
+
+	records := make([]json.RawMessage, len(rawRecords))
+	for i, raw := range rawRecords {
+		record, err := sReq.dataset.Decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// probeCount issues a cheap count(1) request so the caller knows how
+// many pages to dispatch before fetching any of them.
+func probeCount(ctx context.Context, client *retryingClient, sReq searchRequest) (int, error) {
+	params := sReq.query.ForCount().Values()
+
+	body, err := doRequest(ctx, client, sReq.dataset.BaseURL(), sReq.appToken, params)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []map[string]string
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return 0, fmt.Errorf("error unmarshaling count response: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(rows[0]["count"])
+	if err != nil {
+		return 0, fmt.Errorf("error parsing count response: %w", err)
+	}
+	return count, nil
+}
+
+// doRequest performs a single GET against the Socrata API and returns
+// the raw response body.
+func doRequest(ctx context.Context, client *retryingClient, baseURL, appToken string, params url.Values) ([]byte, error) {
+	fullURL := baseURL + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("X-App-Token", appToken)
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("error making HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api returned a non-200 status code: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	return body, nil
+}
+
+// emitAsCompleted forwards each page's records to sReq.records as soon
+// as the page finishes, with no ordering guarantee across pages.
+func emitAsCompleted(ctx context.Context, sReq searchRequest, results <-chan pageResult, cancel context.CancelFunc) {
+	for res := range results {
+		if res.err != nil {
+			sReq.errs <- res.err
+			cancel()
+			continue
+		}
+		for _, record := range res.records {
+			select {
+			case sReq.records <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// emitOrdered buffers out-of-order pages in a min-heap keyed by offset
+// and only forwards records once every preceding page has been emitted,
+// so output order matches the dataset's offset order.
+func emitOrdered(ctx context.Context, sReq searchRequest, results <-chan pageResult, cancel context.CancelFunc) {
+	pending := &pageResultHeap{}
+	heap.Init(pending)
+	next := 0
+
+	for res := range results {
+		if res.err != nil {
+			sReq.errs <- res.err
+			cancel()
+			continue
+		}
+		heap.Push(pending, res)
+
+		for pending.Len() > 0 && (*pending)[0].offset == next {
+			ready := heap.Pop(pending).(pageResult)
+			for _, record := range ready.records {
+				select {
+				case sReq.records <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+			next += len(ready.records)
+		}
+	}
+}
+
+// pageResultHeap orders pageResults by offset so emitOrdered can replay
+// them in the sequence they appear in the dataset.
+type pageResultHeap []pageResult
+
+func (h pageResultHeap) Len() int            { return len(h) }
+func (h pageResultHeap) Less(i, j int) bool  { return h[i].offset < h[j].offset }
+func (h pageResultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *pageResultHeap) Push(x interface{}) { *h = append(*h, x.(pageResult)) }
+func (h *pageResultHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
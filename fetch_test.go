@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errTest = errors.New("boom")
+
+func TestBuildPages(t *testing.T) {
+	pages := buildPages(250, 100)
+	want := []page{{offset: 0, limit: 100}, {offset: 100, limit: 100}, {offset: 200, limit: 50}}
+	if len(pages) != len(want) {
+		t.Fatalf("buildPages() = %+v, want %+v", pages, want)
+	}
+	for i, p := range pages {
+		if p != want[i] {
+			t.Errorf("buildPages()[%d] = %+v, want %+v", i, p, want[i])
+		}
+	}
+}
+
+func TestBuildPagesZeroPageSizeIsOneBigPage(t *testing.T) {
+	pages := buildPages(50, 0)
+	want := []page{{offset: 0, limit: 50}}
+	if len(pages) != 1 || pages[0] != want[0] {
+		t.Fatalf("buildPages() = %+v, want %+v", pages, want)
+	}
+}
+
+func rawRecord(i int) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"i":%d}`, i))
+}
+
+// emitOrdered must replay pages in offset order even when they arrive
+// out of order.
+func TestEmitOrderedReplaysByOffset(t *testing.T) {
+	records := make(chan json.RawMessage, 10)
+	errs := make(chan error, 1)
+	sReq := searchRequest{records: records, errs: errs}
+
+	results := make(chan pageResult, 3)
+	results <- pageResult{offset: 2, records: []json.RawMessage{rawRecord(2)}}
+	results <- pageResult{offset: 0, records: []json.RawMessage{rawRecord(0)}}
+	results <- pageResult{offset: 1, records: []json.RawMessage{rawRecord(1)}}
+	close(results)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emitOrdered(ctx, sReq, results, cancel)
+	close(records)
+
+	var got []json.RawMessage
+	for r := range records {
+		got = append(got, r)
+	}
+
+	want := []string{`{"i":0}`, `{"i":1}`, `{"i":2}`}
+	if len(got) != len(want) {
+		t.Fatalf("emitOrdered() emitted %d records, want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if string(r) != want[i] {
+			t.Errorf("emitOrdered() record %d = %s, want %s", i, r, want[i])
+		}
+	}
+}
+
+func TestEmitAsCompletedForwardsErrorsAndCancels(t *testing.T) {
+	records := make(chan json.RawMessage, 10)
+	errs := make(chan error, 1)
+	sReq := searchRequest{records: records, errs: errs}
+
+	results := make(chan pageResult, 1)
+	results <- pageResult{offset: 0, err: errTest}
+	close(results)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := false
+	wrappedCancel := func() { canceled = true; cancel() }
+
+	emitAsCompleted(ctx, sReq, results, wrappedCancel)
+	close(records)
+	close(errs)
+
+	if !canceled {
+		t.Error("emitAsCompleted() did not cancel on a page error")
+	}
+	if err := <-errs; err != errTest {
+		t.Errorf("emitAsCompleted() forwarded error %v, want %v", err, errTest)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Sc00ba/texas_license_search/datasource"
+	"github.com/Sc00ba/texas_license_search/soql"
+)
+
+// repeatedFlag accumulates every occurrence of a flag that may be
+// passed more than once, e.g. `--eq a=1 --eq b=2`.
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string { return strings.Join(*r, ", ") }
+
+func (r *repeatedFlag) Set(s string) error {
+	*r = append(*r, s)
+	return nil
+}
+
+// splitFieldValue splits a "field=value" flag argument in two.
+func splitFieldValue(flagName, arg string) (field, value string, err error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("--%s expects field=value, got %q", flagName, arg)
+	}
+	return parts[0], parts[1], nil
+}
+
+// registerFieldFlags declares one flag per dataset field (named after
+// FieldSpec.Flag, e.g. "-st" for license_subtype) on fs, so adding a
+// dataset's fields to the CLI requires no Go changes. It returns the
+// bound values, keyed by each field's column Name rather than its flag,
+// for buildPredicate and matchesFilters to read back.
+func registerFieldFlags(fs *flag.FlagSet, fields []datasource.FieldSpec) map[string]*string {
+	values := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		values[f.Name] = fs.String(f.Flag, "", f.Help)
+	}
+	return values
+}
+
+// buildPredicate assembles the full $where predicate from both the
+// per-field flags generated from the dataset's field spec (preserved as
+// case-insensitive substring matches for backward compatibility) and
+// the explicitly-typed --eq/--prefix/--in/--between flags.
+func buildPredicate(sReq searchRequest) (soql.Predicate, error) {
+	var predicates []soql.Predicate
+
+	for _, f := range sReq.dataset.Fields() {
+		if value := sReq.fieldFilters[f.Name]; value != "" {
+			predicates = append(predicates, soql.Contains{Column: f.Name, Value: value})
+		}
+	}
+
+	for _, arg := range sReq.eqFilters {
+		field, value, err := splitFieldValue("eq", arg)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, soql.Eq{Column: field, Value: value})
+	}
+
+	for _, arg := range sReq.prefixFilters {
+		field, value, err := splitFieldValue("prefix", arg)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, soql.Prefix{Column: field, Value: value})
+	}
+
+	for _, arg := range sReq.inFilters {
+		field, value, err := splitFieldValue("in", arg)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, soql.In{Column: field, Values: strings.Split(value, ",")})
+	}
+
+	for _, arg := range sReq.betweenFilters {
+		field, value, err := splitFieldValue("between", arg)
+		if err != nil {
+			return nil, err
+		}
+		bounds := strings.SplitN(value, "..", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("--between expects field=low..high, got %q", arg)
+		}
+		predicates = append(predicates, soql.Between{Column: field, Low: bounds[0], High: bounds[1]})
+	}
+
+	if len(predicates) == 0 {
+		return nil, nil
+	}
+	return soql.And(predicates), nil
+}
+
+// parseOrder splits a "field:asc" or "field:desc" --order argument.
+// A bare field name defaults to ascending.
+func parseOrder(arg string) (field string, desc bool, err error) {
+	if arg == "" {
+		return "", false, nil
+	}
+	parts := strings.SplitN(arg, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], false, nil
+	}
+	switch strings.ToLower(parts[1]) {
+	case "asc":
+		return parts[0], false, nil
+	case "desc":
+		return parts[0], true, nil
+	default:
+		return "", false, fmt.Errorf("--order expects field, field:asc, or field:desc, got %q", arg)
+	}
+}
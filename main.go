@@ -5,41 +5,106 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/tidwall/pretty"
-)
-
-const (
-	apiBaseURL = "https://data.texas.gov/resource/7358-krk7.json"
+	"github.com/Sc00ba/texas_license_search/datasource"
+	"github.com/Sc00ba/texas_license_search/soql"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		runCacheCommand(os.Args[2:])
+		return
+	}
+
 	appToken := os.Getenv("APP_TOKEN")
 	if appToken == "" {
 		log.Fatal("Didn't find required APP_TOKEN in env")
 	}
 
-	expDate := flag.String("e", "", "The expiration date (eg. 12/16/2025)")
-	licenseNumber := flag.String("n", "", "The license number (eg. 90210)")
-	licenseType := flag.String("t", "", "The license type to search for (eg. A/C Technician)")
-	businessCounty := flag.String("c", "", "The business county (eg. HARRIS)")
-	licenseSubType := flag.String("st", "", "The license sub-type (eg. REG)")
-	businessName := flag.String("bn", "", "The business name (eg. BOB'S PLUMBING)")
-	ownerName := flag.String("on", "", "The owner name (eg. BOBS, BOBBY)")
+	// The dataset must be resolved before the rest of flag.Parse runs,
+	// since its field spec drives which per-field flags (-e, -st, ...)
+	// get registered below.
+	preScanDataset, preScanDatasetFile := preScanDatasetFlags(os.Args[1:])
+
+	registry, err := datasource.NewRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if preScanDatasetFile != "" {
+		if err := registry.LoadFile(preScanDatasetFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	ds, err := registry.Get(preScanDataset)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Registered so --dataset/--dataset-file show up in -h and flag.Parse
+	// doesn't reject them; preScanDatasetFlags above is what actually
+	// determines ds, since it has to run before these per-field flags
+	// are known.
+	flag.String("dataset", preScanDataset, "Dataset to search (see --dataset-file to add your own)")
+	flag.String("dataset-file", preScanDatasetFile, "Path to a YAML or JSON dataset definition to register under --dataset")
+	fieldFilters := registerFieldFlags(flag.CommandLine, ds.Fields())
 	timeOutSecs := flag.Int("timeout", 30, "The timeout in seconds")
 	var limit int
 	flag.IntVar(&limit, "limit", 0, "The max records to retrieve")
+	var output string
+	flag.StringVar(&output, "o", "pretty", "Output format: pretty, json, ndjson, or csv")
+	flag.StringVar(&output, "output", "pretty", "Output format: pretty, json, ndjson, or csv")
+	var fieldsFlag string
+	flag.StringVar(&fieldsFlag, "fields", "", "Comma-separated columns for csv output (default: union of keys seen)")
+
+	var eqFilters, prefixFilters, inFilters, betweenFilters repeatedFlag
+	flag.Var(&eqFilters, "eq", "field=value exact-match filter (repeatable)")
+	flag.Var(&prefixFilters, "prefix", "field=value prefix-match filter (repeatable)")
+	flag.Var(&inFilters, "in", "field=a,b,c membership filter (repeatable)")
+	flag.Var(&betweenFilters, "between", "field=low..high range filter (repeatable, eg. exp_date=01/01/2024..12/31/2024)")
+	selectFlag := flag.String("select", "", "Comma-separated columns to return ($select)")
+	orderFlag := flag.String("order", "", "Column to sort by, optionally suffixed :asc or :desc ($order)")
+	fullText := flag.String("q", "", "Full-text search across the dataset ($q)")
+	concurrency := flag.Int("concurrency", 4, "Number of pages to fetch in parallel")
+	ordered := flag.Bool("ordered", false, "Reassemble pages in offset order before emitting records")
+	qps := flag.Float64("qps", 2, "Max requests per second shared across all workers")
+	burst := flag.Int("burst", 4, "Max burst size for the rate limiter")
+	maxRetries := flag.Int("max-retries", defaultRetryConfig.maxRetries, "Max retries for a failed request")
+	initialBackoff := flag.Duration("initial-backoff", defaultRetryConfig.initialBackoff, "Initial backoff delay before the first retry")
+	maxBackoff := flag.Duration("max-backoff", defaultRetryConfig.maxBackoff, "Max backoff delay between retries")
+	backoffFactor := flag.Float64("backoff-factor", defaultRetryConfig.factor, "Backoff multiplier applied after each retry")
+	cachePath := flag.String("cache", "", "Query a local cache (see the cache subcommand) instead of the network")
+	refreshIfOlderThan := flag.Duration("refresh-if-older-than", 0, "With --cache, re-sync first if the cache is older than this")
 	flag.Parse()
 
+	format, err := parseOutputFormat(output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var fields []string
+	if fieldsFlag != "" {
+		fields = strings.Split(fieldsFlag, ",")
+	}
+
+	fieldValues := make(map[string]string, len(fieldFilters))
+	for name, value := range fieldFilters {
+		fieldValues[name] = *value
+	}
+
+	orderColumn, orderDesc, err := parseOrder(*orderFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if orderColumn == "" {
+		orderColumn = ds.DefaultSort()
+	}
+
 	recordsPerRequest := 5000
 	if limit > 0 && limit < recordsPerRequest {
 		recordsPerRequest = limit
@@ -49,21 +114,51 @@ func main() {
 	errs := make(chan error, 1)
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
-	go searchRecords(ctx, searchRequest{
+	sReq := searchRequest{
 		appToken:          appToken,
 		records:           records,
 		errs:              errs,
 		timeOutSecs:       *timeOutSecs,
 		limit:             limit,
 		recordsPerRequest: recordsPerRequest,
-		expDate:           *expDate,
-		licenseNumber:     *licenseNumber,
-		licenseType:       *licenseType,
-		businessCounty:    *businessCounty,
-		licenseSubType:    *licenseSubType,
-		businessName:      *businessName,
-		ownerName:         *ownerName,
-	})
+		fieldFilters:      fieldValues,
+		eqFilters:         eqFilters,
+		prefixFilters:     prefixFilters,
+		inFilters:         inFilters,
+		betweenFilters:    betweenFilters,
+		concurrency:       *concurrency,
+		ordered:           *ordered,
+		qps:               *qps,
+		burst:             *burst,
+		retry: retryConfig{
+			initialBackoff: *initialBackoff,
+			maxBackoff:     *maxBackoff,
+			factor:         *backoffFactor,
+			maxRetries:     *maxRetries,
+		},
+		metrics:            &fetchMetrics{},
+		dataset:            ds,
+		refreshIfOlderThan: *refreshIfOlderThan,
+	}
+
+	predicate, err := buildPredicate(sReq)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	query := soql.NewQuery().Where(predicate).OrderBy(orderColumn, orderDesc).FullText(*fullText)
+	if *selectFlag != "" {
+		query = query.Select(strings.Split(*selectFlag, ",")...)
+	}
+	sReq.query = query
+
+	if *cachePath != "" {
+		go searchCache(ctx, sReq, *cachePath)
+	} else {
+		go searchRecords(ctx, sReq)
+	}
+
+	writer := newRecordWriter(format, os.Stdout, os.Stderr, fields)
 
 	count := 0
 	for {
@@ -71,7 +166,9 @@ func main() {
 		case record, ok := <-records:
 			if ok {
 				count++
-				fmt.Fprintf(os.Stderr, "%s\n", pretty.Color(pretty.Pretty(record), nil))
+				if err := writer.writeRecord(record); err != nil {
+					fmt.Fprintf(os.Stderr, "error writing record: %v\n", err)
+				}
 			} else {
 				records = nil
 			}
@@ -88,155 +185,67 @@ func main() {
 		}
 	}
 
-	fmt.Printf("Found %d total licenses\n", count)
-}
+	if err := writer.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error finalizing output: %v\n", err)
+	}
 
-type searchRequest struct {
-	appToken          string
-	records           chan<- json.RawMessage
-	errs              chan<- error
-	timeOutSecs       int
-	limit             int
-	recordsPerRequest int
-	expDate           string
-	licenseNumber     string
-	licenseType       string
-	businessCounty    string
-	licenseSubType    string
-	businessName      string
-	ownerName         string
+	summary := format.summaryWriter(os.Stdout, os.Stderr)
+	fmt.Fprintf(summary, "Found %d total licenses\n", count)
+	fmt.Fprintf(summary, "Requests: %d, retries: %d, throttled: %d, avg latency: %s\n",
+		sReq.metrics.requests, sReq.metrics.retries, sReq.metrics.throttled, sReq.metrics.averageLatency())
 }
 
-func searchRecords(ctx context.Context, sReq searchRequest) {
-	defer close(sReq.records)
-	defer close(sReq.errs)
-	client := http.Client{Timeout: time.Duration(sReq.timeOutSecs) * time.Second}
-	recordsFound := 0
-	offset := 0
-	for {
-		select {
-		case <-ctx.Done():
-			sReq.errs <- ctx.Err()
-			return
-		default:
-			recordsPerRequest := sReq.recordsPerRequest
-			if sReq.limit > 0 {
-				remaining := sReq.limit - recordsFound
-				if remaining < recordsPerRequest {
-					recordsPerRequest = remaining
-				}
-			}
-
-			if recordsPerRequest == 0 {
-				return
-			}
-
-			whereClause := buildWhereClause(sReq)
-
-			params := url.Values{}
-			if whereClause != "" {
-				params.Add("$where", whereClause)
-			}
-
-			params.Add("$limit", fmt.Sprintf("%d", recordsPerRequest))
-			params.Add("$offset", fmt.Sprintf("%d", offset))
-
-			fullURL := apiBaseURL + "?" + params.Encode()
-
-			req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
-			if err != nil {
-				sReq.errs <- fmt.Errorf("error creating HTTP request: %w", err)
-				return
-			}
-
-			req.Header.Add("Accept", "application/json")
-			req.Header.Add("X-App-Token", sReq.appToken)
-
-			resp, err := client.Do(req)
-			if err != nil {
-				sReq.errs <- fmt.Errorf("error making HTTP request: %w", err)
-				return
-			}
-
-			if resp.StatusCode != http.StatusOK {
-				resp.Body.Close()
-				sReq.errs <- fmt.Errorf("api returned a non-200 status code: %d %s", resp.StatusCode, resp.Status)
-				return
-			}
-
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				sReq.errs <- fmt.Errorf("error reading response body: %w", err)
-				return
-			}
-
-			var recordsRetrieved []json.RawMessage
-			if err := json.Unmarshal(body, &recordsRetrieved); err != nil {
-				sReq.errs <- fmt.Errorf("error unmarshaling JSON: %w", err)
-				return
-			}
-
-			if len(recordsRetrieved) == 0 {
-				return
+// preScanDatasetFlags reads --dataset and --dataset-file out of args
+// without fully parsing them, so the chosen dataset's field spec can be
+// loaded before the per-field flags it generates are registered on the
+// main FlagSet. Defaults match the flags declared afterward.
+func preScanDatasetFlags(args []string) (datasetName, datasetFile string) {
+	datasetName = "tdlr-licenses"
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		switch name {
+		case "dataset":
+			if hasValue {
+				datasetName = value
+			} else if i+1 < len(args) {
+				i++
+				datasetName = args[i]
 			}
-
-			for _, record := range recordsRetrieved {
-				select {
-				case <-ctx.Done():
-					sReq.errs <- ctx.Err()
-					return
-				case sReq.records <- record:
-				}
-				recordsFound++
+		case "dataset-file":
+			if hasValue {
+				datasetFile = value
+			} else if i+1 < len(args) {
+				i++
+				datasetFile = args[i]
 			}
-
-			offset += len(recordsRetrieved)
 		}
 	}
+	return datasetName, datasetFile
 }
 
-func buildWhereClause(sReq searchRequest) string {
-	var conditions []string
-
-	if sReq.expDate != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(license_expiration_date_mmddccyy) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.expDate, "'", "''"))))
-	}
-
-	if sReq.licenseNumber != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(license_number) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.licenseNumber, "'", "''"))))
-	}
-
-	if sReq.licenseType != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(license_type) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.licenseType, "'", "''"))))
-	}
-
-	if sReq.businessCounty != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(business_county) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.businessCounty, "'", "''"))))
-	}
-
-	if sReq.licenseSubType != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(license_subtype) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.licenseSubType, "'", "''"))))
-	}
-
-	if sReq.businessName != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(business_name) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.businessName, "'", "''"))))
-	}
-
-	if sReq.ownerName != "" {
-		conditions = append(conditions, fmt.Sprintf("upper(owner_name) like '%%%s%%'",
-			strings.ToUpper(strings.ReplaceAll(sReq.ownerName, "'", "''"))))
-	}
-
-	if len(conditions) == 0 {
-		return ""
-	}
-
-	return strings.Join(conditions, " AND ")
+type searchRequest struct {
+	appToken           string
+	records            chan<- json.RawMessage
+	errs               chan<- error
+	timeOutSecs        int
+	limit              int
+	recordsPerRequest  int
+	fieldFilters       map[string]string
+	eqFilters          []string
+	prefixFilters      []string
+	inFilters          []string
+	betweenFilters     []string
+	query              *soql.Query
+	concurrency        int
+	ordered            bool
+	qps                float64
+	burst              int
+	retry              retryConfig
+	metrics            *fetchMetrics
+	dataset            datasource.Dataset
+	refreshIfOlderThan time.Duration
 }
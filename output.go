@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/tidwall/pretty"
+)
+
+// outputFormat selects how records are rendered as they arrive on the
+// records channel.
+type outputFormat string
+
+const (
+	formatPretty outputFormat = "pretty"
+	formatJSON   outputFormat = "json"
+	formatNDJSON outputFormat = "ndjson"
+	formatCSV    outputFormat = "csv"
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch outputFormat(s) {
+	case formatPretty, formatJSON, formatNDJSON, formatCSV:
+		return outputFormat(s), nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (want pretty, json, ndjson, or csv)", s)
+	}
+}
+
+// summaryWriter reports whether the human-readable "Found N total licenses"
+// summary belongs on stdout or stderr for a given format. Machine-readable
+// formats own stdout, so the summary moves to stderr instead.
+func (f outputFormat) summaryWriter(stdout, stderr io.Writer) io.Writer {
+	if f == formatPretty {
+		return stdout
+	}
+	return stderr
+}
+
+// recordWriter streams API records to an output in a particular format.
+// Implementations are not safe for concurrent use.
+type recordWriter interface {
+	writeRecord(record json.RawMessage) error
+	close() error
+}
+
+// newRecordWriter constructs the recordWriter for the requested format.
+// fields, if non-empty, fixes the column order for csv output; otherwise
+// csv derives it from the union of keys seen across all records.
+func newRecordWriter(format outputFormat, stdout, stderr io.Writer, fields []string) recordWriter {
+	switch format {
+	case formatJSON:
+		return &jsonArrayWriter{w: bufio.NewWriter(stdout)}
+	case formatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(stdout)}
+	case formatCSV:
+		return &csvWriter{w: stdout, fields: fields}
+	default:
+		return &prettyWriter{w: stderr}
+	}
+}
+
+// prettyWriter preserves the tool's original behavior: colorized,
+// indented JSON on stderr, one record at a time.
+type prettyWriter struct {
+	w io.Writer
+}
+
+func (p *prettyWriter) writeRecord(record json.RawMessage) error {
+	_, err := fmt.Fprintf(p.w, "%s\n", pretty.Color(pretty.Pretty(record), nil))
+	return err
+}
+
+func (p *prettyWriter) close() error { return nil }
+
+// ndjsonWriter emits one compact JSON record per line, suitable for
+// streaming into `jq` or other line-oriented tools.
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+func (n *ndjsonWriter) writeRecord(record json.RawMessage) error {
+	if _, err := n.w.Write(record); err != nil {
+		return err
+	}
+	return n.w.WriteByte('\n')
+}
+
+func (n *ndjsonWriter) close() error { return n.w.Flush() }
+
+// jsonArrayWriter streams records as a single well-formed JSON array,
+// writing each element as it arrives rather than buffering the whole
+// result set in memory.
+type jsonArrayWriter struct {
+	w     *bufio.Writer
+	wrote bool
+}
+
+func (j *jsonArrayWriter) writeRecord(record json.RawMessage) error {
+	if !j.wrote {
+		if _, err := j.w.WriteString("["); err != nil {
+			return err
+		}
+		j.wrote = true
+	} else {
+		if _, err := j.w.WriteString(","); err != nil {
+			return err
+		}
+	}
+	_, err := j.w.Write(record)
+	return err
+}
+
+func (j *jsonArrayWriter) close() error {
+	if !j.wrote {
+		if _, err := j.w.WriteString("[]"); err != nil {
+			return err
+		}
+	} else if _, err := j.w.WriteString("]"); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// csvWriter buffers records so it can derive a header row from the union
+// of top-level keys before emitting a single row per record, unless an
+// explicit column list was supplied via --fields.
+type csvWriter struct {
+	w       io.Writer
+	fields  []string
+	records []map[string]json.RawMessage
+}
+
+func (c *csvWriter) writeRecord(record json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(record, &fields); err != nil {
+		return fmt.Errorf("error decoding record for csv output: %w", err)
+	}
+	c.records = append(c.records, fields)
+	return nil
+}
+
+func (c *csvWriter) close() error {
+	header := c.fields
+	if len(header) == 0 {
+		header = c.unionFields()
+	}
+
+	cw := csv.NewWriter(c.w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range c.records {
+		row := make([]string, len(header))
+		for i, field := range header {
+			row[i] = rawJSONToCSVValue(record[field])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (c *csvWriter) unionFields() []string {
+	seen := make(map[string]struct{})
+	var fields []string
+	for _, record := range c.records {
+		for field := range record {
+			if _, ok := seen[field]; !ok {
+				seen[field] = struct{}{}
+				fields = append(fields, field)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// rawJSONToCSVValue renders a JSON scalar as a CSV cell, stripping the
+// surrounding quotes from strings so values don't end up double-encoded.
+func rawJSONToCSVValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, valid := range []string{"pretty", "json", "ndjson", "csv"} {
+		if _, err := parseOutputFormat(valid); err != nil {
+			t.Errorf("parseOutputFormat(%q) error = %v, want nil", valid, err)
+		}
+	}
+	if _, err := parseOutputFormat("xml"); err == nil {
+		t.Error("parseOutputFormat(\"xml\") error = nil, want an error")
+	}
+}
+
+func TestSummaryWriterPrettyUsesStdout(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if w := formatPretty.summaryWriter(&stdout, &stderr); w != &stdout {
+		t.Error("summaryWriter() for pretty did not return stdout")
+	}
+	if w := formatJSON.summaryWriter(&stdout, &stderr); w != &stderr {
+		t.Error("summaryWriter() for json did not return stderr")
+	}
+}
+
+func TestJSONArrayWriterEmitsWellFormedArray(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRecordWriter(formatJSON, &buf, &buf, nil)
+
+	if err := w.writeRecord(json.RawMessage(`{"a":1}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.writeRecord(json.RawMessage(`{"a":2}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	want := `[{"a":1},{"a":2}]`
+	if got := buf.String(); got != want {
+		t.Errorf("jsonArrayWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestJSONArrayWriterEmptyIsEmptyArray(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRecordWriter(formatJSON, &buf, &buf, nil)
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	if got := buf.String(); got != "[]" {
+		t.Errorf("jsonArrayWriter empty output = %q, want []", got)
+	}
+}
+
+func TestNDJSONWriterOneRecordPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRecordWriter(formatNDJSON, &buf, &buf, nil)
+
+	if err := w.writeRecord(json.RawMessage(`{"a":1}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.writeRecord(json.RawMessage(`{"a":2}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	want := "{\"a\":1}\n{\"a\":2}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("ndjsonWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestCSVWriterUnionOfKeysIsSortedHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRecordWriter(formatCSV, &buf, &buf, nil)
+
+	if err := w.writeRecord(json.RawMessage(`{"license_number":"1","business_name":"Alpha"}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.writeRecord(json.RawMessage(`{"license_number":"2","owner_name":"Bobby"}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	want := []string{
+		"business_name,license_number,owner_name",
+		"Alpha,1,",
+		",2,Bobby",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("csvWriter output had %d lines, want %d: %q", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		if line != want[i] {
+			t.Errorf("csvWriter line %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestCSVWriterExplicitFieldsOverridesOrder(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRecordWriter(formatCSV, &buf, &buf, []string{"owner_name", "license_number"})
+
+	if err := w.writeRecord(json.RawMessage(`{"license_number":"1","owner_name":"Bobby","business_name":"Alpha"}`)); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	want := "owner_name,license_number\nBobby,1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csvWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestRawJSONToCSVValueUnquotesStrings(t *testing.T) {
+	cases := map[string]string{
+		`"hello"`: "hello",
+		`42`:      "42",
+		`true`:    "true",
+		`null`:    "",
+		``:        "",
+	}
+	for raw, want := range cases {
+		if got := rawJSONToCSVValue(json.RawMessage(raw)); got != want {
+			t.Errorf("rawJSONToCSVValue(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// retryConfig controls the exponential backoff applied to retryable
+// HTTP responses and network errors.
+type retryConfig struct {
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	factor         float64
+	maxRetries     int
+}
+
+var defaultRetryConfig = retryConfig{
+	initialBackoff: 500 * time.Millisecond,
+	maxBackoff:     30 * time.Second,
+	factor:         2,
+	maxRetries:     5,
+}
+
+// backoff returns the delay before retry attempt n (0-indexed),
+// exponentially increasing up to maxBackoff with +/-25% jitter so
+// concurrent workers don't retry in lockstep.
+func (c retryConfig) backoff(attempt int) time.Duration {
+	d := float64(c.initialBackoff) * math.Pow(c.factor, float64(attempt))
+	if d > float64(c.maxBackoff) {
+		d = float64(c.maxBackoff)
+	}
+	jittered := d * (0.75 + rand.Float64()*0.5)
+	return time.Duration(jittered)
+}
+
+// fetchMetrics accumulates counters across every worker for the final
+// summary: how much the client had to retry or back off, and how fast
+// the API responded on average.
+type fetchMetrics struct {
+	requests     int64
+	retries      int64
+	throttled    int64
+	totalLatency int64 // nanoseconds, across all requests
+}
+
+func (m *fetchMetrics) recordRequest(d time.Duration) {
+	atomic.AddInt64(&m.requests, 1)
+	atomic.AddInt64(&m.totalLatency, int64(d))
+}
+
+func (m *fetchMetrics) recordRetry() { atomic.AddInt64(&m.retries, 1) }
+
+func (m *fetchMetrics) recordThrottled() { atomic.AddInt64(&m.throttled, 1) }
+
+func (m *fetchMetrics) averageLatency() time.Duration {
+	requests := atomic.LoadInt64(&m.requests)
+	if requests == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&m.totalLatency) / requests)
+}
+
+// retryingClient wraps an *http.Client with a shared rate limiter and
+// exponential backoff retries, honoring Socrata's 429 throttling and
+// any Retry-After it sends.
+type retryingClient struct {
+	http    *http.Client
+	limiter *rate.Limiter
+	retry   retryConfig
+	metrics *fetchMetrics
+}
+
+func newRetryingClient(timeout time.Duration, qps float64, burst int, retry retryConfig, metrics *fetchMetrics) *retryingClient {
+	return &retryingClient{
+		http:    &http.Client{Timeout: timeout},
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+		retry:   retry,
+		metrics: metrics,
+	}
+}
+
+// Do executes req, retrying on 429/5xx responses and network errors
+// with exponential backoff, and blocking on the shared rate limiter
+// before every attempt including the first.
+func (c *retryingClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := c.http.Do(req)
+		c.metrics.recordRequest(time.Since(start))
+
+		retryable := false
+		wait := c.retry.backoff(attempt)
+
+		switch {
+		case err != nil:
+			lastErr = err
+			retryable = true
+		case isRetryableStatus(resp.StatusCode):
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.metrics.recordThrottled()
+			}
+			lastErr = fmt.Errorf("api returned a non-200 status code: %d %s", resp.StatusCode, resp.Status)
+			if ra := retryAfter(resp.Header.Get("Retry-After")); ra > 0 {
+				wait = ra
+			}
+			resp.Body.Close()
+			retryable = true
+		default:
+			return resp, nil
+		}
+
+		if !retryable || attempt >= c.retry.maxRetries {
+			return resp, lastErr
+		}
+
+		c.metrics.recordRetry()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header, which Socrata may send as
+// either a number of seconds or an HTTP-date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryingClientRetriesOn429ThenSucceeds(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	metrics := &fetchMetrics{}
+	client := newRetryingClient(time.Second, 1000, 1000, retryConfig{
+		initialBackoff: time.Millisecond,
+		maxBackoff:     10 * time.Millisecond,
+		factor:         2,
+		maxRetries:     3,
+	}, metrics)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if attempts != 2 {
+		t.Errorf("server saw %d attempts, want 2", attempts)
+	}
+	if metrics.throttled != 1 {
+		t.Errorf("metrics.throttled = %d, want 1", metrics.throttled)
+	}
+	if metrics.retries != 1 {
+		t.Errorf("metrics.retries = %d, want 1", metrics.retries)
+	}
+}
+
+func TestRetryingClientGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	metrics := &fetchMetrics{}
+	client := newRetryingClient(time.Second, 1000, 1000, retryConfig{
+		initialBackoff: time.Millisecond,
+		maxBackoff:     5 * time.Millisecond,
+		factor:         2,
+		maxRetries:     2,
+	}, metrics)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(context.Background(), req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("Do() error = nil, want a non-200 status error")
+	}
+
+	// One initial attempt plus maxRetries retries.
+	if attempts != 3 {
+		t.Errorf("server saw %d attempts, want 3", attempts)
+	}
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+	}
+	for code, want := range cases {
+		if got := isRetryableStatus(code); got != want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestBackoffGrowsExponentiallyUpToMax(t *testing.T) {
+	cfg := retryConfig{initialBackoff: 100 * time.Millisecond, maxBackoff: 300 * time.Millisecond, factor: 2}
+
+	// Jitter is +/-25%, so check bounds rather than an exact value.
+	if d := cfg.backoff(0); d < 75*time.Millisecond || d > 125*time.Millisecond {
+		t.Errorf("backoff(0) = %v, want in [75ms, 125ms]", d)
+	}
+	if d := cfg.backoff(5); d < 225*time.Millisecond || d > 375*time.Millisecond {
+		t.Errorf("backoff(5) = %v, want capped near maxBackoff (300ms +/-25%%)", d)
+	}
+}
@@ -0,0 +1,34 @@
+package soql
+
+import "testing"
+
+// ForCount must drop $order/$group when building a count(1) probe:
+// Socrata rejects an aggregate $select alongside an unaggregated
+// $order or $group.
+func TestForCountDropsOrderAndGroup(t *testing.T) {
+	q := NewQuery().
+		Where(Eq{Column: "license_type", Value: "A/C"}).
+		FullText("plumbing").
+		OrderBy("license_number", false).
+		GroupBy("license_type").
+		Select("license_number").
+		Limit(50).
+		Offset(100)
+
+	v := q.ForCount().Values()
+
+	if got := v.Get("$select"); got != "count(1) AS count" {
+		t.Errorf("ForCount() $select = %q, want count(1) AS count", got)
+	}
+	if got := v.Get("$where"); got != `license_type = 'A/C'` {
+		t.Errorf("ForCount() $where = %q, want the original predicate preserved", got)
+	}
+	if got := v.Get("$q"); got != "plumbing" {
+		t.Errorf("ForCount() $q = %q, want the original full-text term preserved", got)
+	}
+	for _, param := range []string{"$order", "$group", "$limit", "$offset"} {
+		if _, ok := v[param]; ok {
+			t.Errorf("ForCount() set %s unexpectedly: %v", param, v)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package soql builds Socrata Query Language ($where, $select, $order,
+// $group, $q) expressions without resorting to hand-rolled string
+// templates, so user-supplied values can't produce malformed or
+// unintended SoQL.
+package soql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Predicate renders a single condition, or a combination of conditions,
+// as the body of a SoQL $where clause.
+type Predicate interface {
+	SoQL() string
+}
+
+// Eq matches rows where Column equals Value exactly.
+type Eq struct {
+	Column string
+	Value  string
+}
+
+func (p Eq) SoQL() string {
+	return fmt.Sprintf("%s = %s", p.Column, quote(p.Value))
+}
+
+// Prefix matches rows where Column starts with Value, case-insensitively.
+type Prefix struct {
+	Column string
+	Value  string
+}
+
+func (p Prefix) SoQL() string {
+	return fmt.Sprintf("upper(%s) like %s", p.Column, quote(escapeLike(strings.ToUpper(p.Value))+"%"))
+}
+
+// Contains matches rows where Column contains Value anywhere,
+// case-insensitively. This is the substring search the tool originally
+// performed for every field.
+type Contains struct {
+	Column string
+	Value  string
+}
+
+func (p Contains) SoQL() string {
+	return fmt.Sprintf("upper(%s) like %s", p.Column, quote("%"+escapeLike(strings.ToUpper(p.Value))+"%"))
+}
+
+// In matches rows where Column equals one of Values.
+type In struct {
+	Column string
+	Values []string
+}
+
+func (p In) SoQL() string {
+	quoted := make([]string, len(p.Values))
+	for i, v := range p.Values {
+		quoted[i] = quote(v)
+	}
+	return fmt.Sprintf("%s in(%s)", p.Column, strings.Join(quoted, ", "))
+}
+
+// GreaterThan matches rows where Column is greater than Value, e.g. for
+// incremental syncs filtering on the Socrata :updated_at system field.
+type GreaterThan struct {
+	Column string
+	Value  string
+}
+
+func (p GreaterThan) SoQL() string {
+	return fmt.Sprintf("%s > %s", p.Column, quote(p.Value))
+}
+
+// Between matches rows where Column falls within [Low, High] inclusive.
+type Between struct {
+	Column    string
+	Low, High string
+}
+
+func (p Between) SoQL() string {
+	return fmt.Sprintf("%s between %s and %s", p.Column, quote(p.Low), quote(p.High))
+}
+
+// IsNull matches rows where Column is null, or where Not is set, where
+// it isn't.
+type IsNull struct {
+	Column string
+	Not    bool
+}
+
+func (p IsNull) SoQL() string {
+	if p.Not {
+		return fmt.Sprintf("%s is not null", p.Column)
+	}
+	return fmt.Sprintf("%s is null", p.Column)
+}
+
+// And requires every one of its predicates to hold.
+type And []Predicate
+
+func (p And) SoQL() string { return join(p, "AND") }
+
+// Or requires at least one of its predicates to hold.
+type Or []Predicate
+
+func (p Or) SoQL() string { return join(p, "OR") }
+
+// Not negates a single predicate.
+type Not struct {
+	Predicate Predicate
+}
+
+func (p Not) SoQL() string {
+	return fmt.Sprintf("not (%s)", p.Predicate.SoQL())
+}
+
+func join(preds []Predicate, op string) string {
+	parts := make([]string, len(preds))
+	for i, p := range preds {
+		parts[i] = "(" + p.SoQL() + ")"
+	}
+	return strings.Join(parts, " "+op+" ")
+}
+
+// quote renders a SoQL string literal, doubling embedded single quotes
+// per SoQL's string literal syntax.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// escapeLike escapes the LIKE wildcard characters % and _, and the
+// backslash escape character itself, so a value can't smuggle in
+// unintended wildcards.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
@@ -0,0 +1,59 @@
+package soql
+
+import "testing"
+
+func TestEqSoQL(t *testing.T) {
+	got := Eq{Column: "license_number", Value: "90210"}.SoQL()
+	want := `license_number = '90210'`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteEscapesEmbeddedQuotes(t *testing.T) {
+	got := Eq{Column: "owner_name", Value: "O'Brien"}.SoQL()
+	want := `owner_name = 'O''Brien'`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestContainsEscapesLikeWildcards(t *testing.T) {
+	got := Contains{Column: "business_name", Value: "100%_off\\"}.SoQL()
+	want := `upper(business_name) like '%100\%\_OFF\\%'`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestInSoQL(t *testing.T) {
+	got := In{Column: "license_type", Values: []string{"A/C", "O'Brien"}}.SoQL()
+	want := `license_type in('A/C', 'O''Brien')`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestBetweenSoQL(t *testing.T) {
+	got := Between{Column: "license_expiration_date_mmddccyy", Low: "01/01/2024", High: "12/31/2024"}.SoQL()
+	want := `license_expiration_date_mmddccyy between '01/01/2024' and '12/31/2024'`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestAndJoinsWithParens(t *testing.T) {
+	got := And{Eq{Column: "a", Value: "1"}, Eq{Column: "b", Value: "2"}}.SoQL()
+	want := `(a = '1') AND (b = '2')`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
+
+func TestGreaterThanSoQL(t *testing.T) {
+	got := GreaterThan{Column: ":updated_at", Value: "2024-01-01T00:00:00.000Z"}.SoQL()
+	want := `:updated_at > '2024-01-01T00:00:00.000Z'`
+	if got != want {
+		t.Errorf("SoQL() = %q, want %q", got, want)
+	}
+}
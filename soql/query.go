@@ -0,0 +1,112 @@
+package soql
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query builds the parameter set for a single Socrata SoQL request.
+// The zero value is an unfiltered, unsorted, unlimited query; use the
+// builder methods to fill it in.
+type Query struct {
+	where     Predicate
+	selects   []string
+	order     string
+	orderDesc bool
+	group     []string
+	fullText  string
+	limit     int
+	offset    int
+}
+
+// NewQuery returns an empty Query ready for the builder methods below.
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Where sets the $where predicate.
+func (q *Query) Where(p Predicate) *Query {
+	q.where = p
+	return q
+}
+
+// Select sets the $select column list.
+func (q *Query) Select(columns ...string) *Query {
+	q.selects = columns
+	return q
+}
+
+// OrderBy sets the $order column and direction.
+func (q *Query) OrderBy(column string, desc bool) *Query {
+	q.order = column
+	q.orderDesc = desc
+	return q
+}
+
+// GroupBy sets the $group column list.
+func (q *Query) GroupBy(columns ...string) *Query {
+	q.group = columns
+	return q
+}
+
+// FullText sets the $q full-text search term.
+func (q *Query) FullText(text string) *Query {
+	q.fullText = text
+	return q
+}
+
+// Limit sets $limit. A value of 0 omits the parameter.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset sets $offset. A value of 0 omits the parameter.
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// ForCount returns a new Query carrying only this query's $where and $q
+// filters, with $select set to a count(1) aggregate. Socrata rejects an
+// aggregate $select alongside an unaggregated $order or $group, so a
+// count probe can't just clone the original query and overwrite $select.
+func (q *Query) ForCount() *Query {
+	return &Query{
+		where:    q.where,
+		fullText: q.fullText,
+		selects:  []string{"count(1) AS count"},
+	}
+}
+
+// Values renders the query as Socrata request parameters.
+func (q *Query) Values() url.Values {
+	v := url.Values{}
+	if q.where != nil {
+		v.Set("$where", q.where.SoQL())
+	}
+	if len(q.selects) > 0 {
+		v.Set("$select", strings.Join(q.selects, ", "))
+	}
+	if q.order != "" {
+		order := q.order
+		if q.orderDesc {
+			order += " DESC"
+		}
+		v.Set("$order", order)
+	}
+	if len(q.group) > 0 {
+		v.Set("$group", strings.Join(q.group, ", "))
+	}
+	if q.fullText != "" {
+		v.Set("$q", q.fullText)
+	}
+	if q.limit > 0 {
+		v.Set("$limit", strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		v.Set("$offset", strconv.Itoa(q.offset))
+	}
+	return v
+}
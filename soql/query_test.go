@@ -0,0 +1,40 @@
+package soql
+
+import "testing"
+
+func TestQueryValues(t *testing.T) {
+	q := NewQuery().
+		Where(Eq{Column: "license_number", Value: "90210"}).
+		OrderBy("license_number", true).
+		Limit(10).
+		Offset(20).
+		FullText("plumbing")
+
+	v := q.Values()
+
+	cases := map[string]string{
+		"$where":  `license_number = '90210'`,
+		"$order":  "license_number DESC",
+		"$limit":  "10",
+		"$offset": "20",
+		"$q":      "plumbing",
+	}
+	for param, want := range cases {
+		if got := v.Get(param); got != want {
+			t.Errorf("Values()[%q] = %q, want %q", param, got, want)
+		}
+	}
+	if _, ok := v["$select"]; ok {
+		t.Errorf("Values() set $select unexpectedly: %v", v)
+	}
+}
+
+func TestQueryValuesOmitsZeroLimitAndOffset(t *testing.T) {
+	v := NewQuery().Values()
+	if _, ok := v["$limit"]; ok {
+		t.Errorf("Values() set $limit for a zero value: %v", v)
+	}
+	if _, ok := v["$offset"]; ok {
+		t.Errorf("Values() set $offset for a zero value: %v", v)
+	}
+}